@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jasonmoo/smlr/pkg/smlr"
+	"github.com/nfnt/resize"
+)
+
+// imageExtensions are the file extensions runBatch considers for
+// processing when walking a directory.
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true,
+	".gif": true, ".tif": true, ".tiff": true, ".bmp": true,
+}
+
+// batchStats accumulates totals across a batch run. All fields are
+// updated with the atomic package since workers run concurrently.
+type batchStats struct {
+	processed, skipped, errored int64
+	bytesIn, bytesOut           int64
+}
+
+// runBatch walks inDir recursively, optimizing every recognized image into
+// the mirrored path under outDir using a pool of *cores workers. Individual
+// file errors are logged and accumulated rather than aborting the run.
+func runBatch(inDir, outDir string, outFormat smlr.Format) {
+
+	start := time.Now()
+
+	resample, err := parseFilter(*filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fitMode, err := parseFit(*fit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	anchorMode, err := parseAnchor(*anchor)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var files []string
+	err = filepath.Walk(inDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		if imageExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	jobs := make(chan string)
+	var stats batchStats
+
+	workers := *cores
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				processBatchFile(path, inDir, outDir, outFormat, resample, fitMode, anchorMode, &stats)
+			}
+		}()
+	}
+
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+
+	fmt.Println()
+	fmt.Println("Completed in", time.Since(start))
+	fmt.Printf("processed: %d, skipped: %d, errored: %d\n",
+		atomic.LoadInt64(&stats.processed), atomic.LoadInt64(&stats.skipped), atomic.LoadInt64(&stats.errored))
+
+	bytesIn := atomic.LoadInt64(&stats.bytesIn)
+	bytesOut := atomic.LoadInt64(&stats.bytesOut)
+	var saved float64
+	if bytesIn > 0 {
+		saved = 100 * (1 - float64(bytesOut)/float64(bytesIn))
+	}
+	fmt.Printf("bytes in: %s, bytes out: %s, saved: %.1f%%\n", human(bytesIn), human(bytesOut), saved)
+
+	if atomic.LoadInt64(&stats.errored) > 0 {
+		os.Exit(1)
+	}
+
+}
+
+// processBatchFile optimizes a single file found during the directory
+// walk and records its outcome in stats. It never aborts the batch; any
+// error is logged and counted.
+func processBatchFile(path, inDir, outDir string, outFormat smlr.Format, resample resize.InterpolationFunction, fitMode smlr.Fit, anchorMode smlr.Anchor, stats *batchStats) {
+
+	rel, err := filepath.Rel(inDir, path)
+	if err != nil {
+		log.Println(path, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+		return
+	}
+	dest := filepath.Join(outDir, rel)
+
+	if !*overwrite {
+		if _, err := os.Stat(dest); err == nil {
+			atomic.AddInt64(&stats.skipped, 1)
+			return
+		}
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println(rel, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+		return
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		log.Println(rel, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+		return
+	}
+
+	result, err := smlr.Optimize(context.Background(), img, smlr.Options{
+		MaxDeviation:     *maxRating,
+		Width:            uint(*width),
+		Height:           uint(*height),
+		Resample:         resample,
+		Fit:              fitMode,
+		Anchor:           anchorMode,
+		Cores:            1,
+		Format:           outFormat,
+		Compare:          comparator(*metric, 1),
+		Source:           src,
+		PreserveMetadata: *preserveMetadata,
+		ApplyOrientation: *applyOrientation,
+	})
+	if err != nil {
+		log.Println(rel, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+		return
+	}
+
+	out := result.Bytes
+	if *skipSmaller && len(out) >= len(src) {
+		out = src
+	}
+
+	atomic.AddInt64(&stats.bytesIn, int64(len(src)))
+	atomic.AddInt64(&stats.bytesOut, int64(len(out)))
+	atomic.AddInt64(&stats.processed, 1)
+
+	if *dryRun {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		log.Println(rel, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+		return
+	}
+
+	if err := ioutil.WriteFile(dest, out, 0644); err != nil {
+		log.Println(rel, "error:", err)
+		atomic.AddInt64(&stats.errored, 1)
+	}
+
+}
@@ -0,0 +1,329 @@
+// Command smlr shrinks an image to the lowest quality, in the chosen
+// output format, that stays within a target perceptual deviation of the
+// source.
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jasonmoo/smlr/pkg/smlr"
+	"github.com/nfnt/resize"
+	_ "golang.org/x/image/bmp"  // registers image.Decode support for BMP
+	_ "golang.org/x/image/tiff" // registers image.Decode support for TIFF
+)
+
+var (
+	maxRating = flag.Float64("max", 1.1, "maximum deviation detected")
+	width     = flag.Int("width", 0, "width to resize to.  omitting either width or height will maintain proportion")
+	height    = flag.Int("height", 0, "height to resize to.  omitting either width or height will maintain proportion")
+	infile    = flag.String("if", "", "file to process")
+	outfile   = flag.String("of", "", "output file")
+	cores     = flag.Int("cores", runtime.NumCPU(), "how many cores to use")
+	metric    = flag.String("metric", "ssim", "quality comparator to use: ssim, msssim, or extern (shells out to compare_pngs)")
+	format    = flag.String("format", "jpeg", "output format: jpeg, png, webp, or avif")
+	filter    = flag.String("filter", "lanczos3", "resample filter: nearest, box, linear, catmullrom, lanczos2, or lanczos3")
+	fit       = flag.String("fit", "scale", "how to apply -width/-height together: scale (distort to fit), fit (letterbox, no crop), or fill (cover and crop)")
+	anchor    = flag.String("anchor", "center", "region to keep when -fit=fill crops: center, top, topleft, or smart (crops around the busiest region)")
+
+	skipSmaller = flag.Bool("skip-smaller", false, "in batch mode, leave the original in place when the optimized output would be larger")
+	dryRun      = flag.Bool("dry-run", false, "in batch mode, report predicted savings without writing any output")
+	overwrite   = flag.Bool("overwrite", false, "in batch mode, reprocess files that already exist in -of (default leaves them alone, so re-runs are idempotent)")
+
+	preserveMetadata = flag.Bool("preserve-metadata", true, "copy EXIF/ICC/XMP metadata from the source into the output")
+	applyOrientation = flag.Bool("apply-orientation", false, "rotate/flip pixels to match EXIF Orientation and clear the tag, instead of copying it through unapplied")
+)
+
+func init() {
+
+	flag.Parse()
+
+	if *infile == "" || *outfile == "" {
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	switch *metric {
+	case "ssim", "msssim", "extern":
+	default:
+		log.Fatalf("unknown -metric %q, want ssim, msssim, or extern", *metric)
+	}
+
+	if _, err := parseFormat(*format); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := parseFilter(*filter); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := parseFit(*fit); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := parseAnchor(*anchor); err != nil {
+		log.Fatal(err)
+	}
+
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+}
+
+// parseFormat resolves the -format flag to a smlr.Format.
+func parseFormat(format string) (smlr.Format, error) {
+	switch format {
+	case "jpeg", "jpg":
+		return smlr.JPEG, nil
+	case "png":
+		return smlr.PNG, nil
+	case "webp":
+		return smlr.WebP, nil
+	case "avif":
+		return smlr.AVIF, nil
+	default:
+		return 0, fmt.Errorf("unknown -format %q, want jpeg, png, webp, or avif", format)
+	}
+}
+
+// parseFilter resolves the -filter flag to a resize.InterpolationFunction.
+// nfnt/resize doesn't expose exact box/linear/catmullrom filters, so those
+// map to their closest available equivalent: box and linear both use
+// Bilinear, and catmullrom uses MitchellNetravali, the closest cubic
+// filter it offers.
+func parseFilter(filter string) (resize.InterpolationFunction, error) {
+	switch filter {
+	case "nearest":
+		return resize.NearestNeighbor, nil
+	case "box", "linear":
+		return resize.Bilinear, nil
+	case "catmullrom":
+		return resize.MitchellNetravali, nil
+	case "lanczos2":
+		return resize.Lanczos2, nil
+	case "lanczos3":
+		return resize.Lanczos3, nil
+	default:
+		return 0, fmt.Errorf("unknown -filter %q, want nearest, box, linear, catmullrom, lanczos2, or lanczos3", filter)
+	}
+}
+
+// parseFit resolves the -fit flag to a smlr.Fit.
+func parseFit(fit string) (smlr.Fit, error) {
+	switch fit {
+	case "scale":
+		return smlr.FitScale, nil
+	case "fit":
+		return smlr.FitContain, nil
+	case "fill":
+		return smlr.FitFill, nil
+	default:
+		return 0, fmt.Errorf("unknown -fit %q, want scale, fit, or fill", fit)
+	}
+}
+
+// parseAnchor resolves the -anchor flag to a smlr.Anchor.
+func parseAnchor(anchor string) (smlr.Anchor, error) {
+	switch anchor {
+	case "center":
+		return smlr.AnchorCenter, nil
+	case "top":
+		return smlr.AnchorTop, nil
+	case "topleft":
+		return smlr.AnchorTopLeft, nil
+	case "smart":
+		return smlr.AnchorSmart, nil
+	default:
+		return 0, fmt.Errorf("unknown -anchor %q, want center, top, topleft, or smart", anchor)
+	}
+}
+
+func main() {
+
+	outFormat, err := parseFormat(*format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ininfo, err := os.Stat(*infile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if ininfo.IsDir() {
+		runBatch(*infile, *outfile, outFormat)
+		return
+	}
+
+	runSingle(*infile, *outfile, outFormat)
+
+}
+
+// runSingle optimizes one file, matching smlr's original -if/-of behavior.
+func runSingle(infile, outfile string, outFormat smlr.Format) {
+
+	start := time.Now()
+
+	raw, err := ioutil.ReadFile(infile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(raw))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick.C:
+				fmt.Print(".")
+			}
+		}
+	}()
+
+	resample, err := parseFilter(*filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fitMode, err := parseFit(*fit)
+	if err != nil {
+		log.Fatal(err)
+	}
+	anchorMode, err := parseAnchor(*anchor)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	result, err := smlr.Optimize(context.Background(), img, smlr.Options{
+		MaxDeviation:     *maxRating,
+		Width:            uint(*width),
+		Height:           uint(*height),
+		Resample:         resample,
+		Fit:              fitMode,
+		Anchor:           anchorMode,
+		Cores:            *cores,
+		Format:           outFormat,
+		Compare:          comparator(*metric, *cores),
+		Source:           raw,
+		PreserveMetadata: *preserveMetadata,
+		ApplyOrientation: *applyOrientation,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(outfile, result.Bytes, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	outinfo, err := os.Stat(outfile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	close(done)
+
+	fmt.Println("\nCompleted in", time.Since(start))
+	fmt.Println("Best", outFormat, "quality:", result.Quality)
+	fmt.Println(infile+":", human(int64(len(raw))))
+	fmt.Println(outfile+":", human(outinfo.Size()))
+
+}
+
+// comparator resolves the -metric flag to a smlr.Comparator, evaluated
+// with up to cores goroutines. "extern" shells out to compare_pngs for
+// users who still want butteraugli instead of the in-process comparators.
+func comparator(metric string, cores int) smlr.Comparator {
+	switch metric {
+	case "msssim":
+		return smlr.NewMSSSIM(cores)
+	case "extern":
+		return externComparator{}
+	default:
+		return smlr.NewSSIM(cores)
+	}
+}
+
+// externComparator shells out to the compare_pngs binary, matching smlr's
+// historical behavior before it grew an in-process comparator.
+type externComparator struct{}
+
+func (externComparator) Compare(ref, test image.Image) float64 {
+
+	refPNG, err := pngTempFile(ref)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(refPNG)
+
+	testPNG, err := pngTempFile(test)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(testPNG)
+
+	var buf bytes.Buffer
+	cmd := exec.Command("compare_pngs", refPNG, testPNG)
+	cmd.Env = []string{}
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		log.Fatal(err)
+	}
+
+	rating, err := strconv.ParseFloat(strings.TrimSpace(buf.String()), 64)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return rating
+
+}
+
+func pngTempFile(img image.Image) (string, error) {
+
+	out, err := ioutil.TempFile(os.TempDir(), "_smlr_")
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+
+	if err := png.Encode(out, img); err != nil {
+		return "", err
+	}
+
+	return out.Name(), nil
+
+}
+
+var sizes = []string{"B", "KB", "MB", "GB", "TB", "PB", "EB"}
+
+func human(b int64) string {
+	var i int
+	n := float64(b)
+	for n >= 1024 {
+		i++
+		n /= 1024
+	}
+	return strconv.FormatFloat(n, 'f', 1, 64) + sizes[i]
+}
@@ -0,0 +1,205 @@
+// Package compare implements in-process perceptual image comparison so
+// callers like smlr's quality search can score a candidate encode against
+// a reference without shelling out to an external tool (e.g. compare_pngs)
+// or round-tripping through temporary PNG files.
+package compare
+
+import (
+	"image"
+	"sync"
+)
+
+const (
+	windowSize = 8
+	stride     = 4
+
+	// SSIM stabilization constants for 8-bit luma, as in the original paper.
+	c1 = (0.01 * 255) * (0.01 * 255)
+	c2 = (0.03 * 255) * (0.03 * 255)
+)
+
+// Compare returns the perceptual deviation between ref and test as 1 - SSIM,
+// so 0 means identical and larger values mean more different, matching the
+// semantics smlr's quality search already expects from maxRating. workers
+// bounds how many goroutines evaluate SSIM windows concurrently; values
+// below 1 are treated as 1.
+func Compare(ref, test image.Image, workers int) float64 {
+	return 1 - ssim(luma(ref), luma(test), workers)
+}
+
+// CompareMultiScale is a coarse multi-scale SSIM (MS-SSIM): it averages SSIM
+// over the original image and two half-resolution downsamples, which is
+// more stable than single-scale SSIM on resized or heavily compressed
+// images. It returns 1 - MS-SSIM, same semantics as Compare.
+func CompareMultiScale(ref, test image.Image, workers int) float64 {
+	a, b := luma(ref), luma(test)
+
+	var sum float64
+	const scales = 3
+	var done int
+	for i := 0; i < scales; i++ {
+		sum += ssim(a, b, workers)
+		done++
+		a, b = a.downsample(), b.downsample()
+		if a.w < windowSize || a.h < windowSize {
+			break
+		}
+	}
+
+	return 1 - sum/float64(done)
+}
+
+// ssim computes the mean SSIM between a and b over a sliding window of
+// windowSize x windowSize pixels, stepping by stride, parallelized across
+// up to workers goroutines.
+func ssim(a, b lumaImage, workers int) float64 {
+	w := a.w
+	if b.w < w {
+		w = b.w
+	}
+	h := a.h
+	if b.h < h {
+		h = b.h
+	}
+
+	// Images smaller than a full window (e.g. a tiny crop/fit target)
+	// can't fit windowSize x windowSize anywhere, so shrink the window to
+	// whatever fits and score the whole image as a single point.
+	winW, winH := windowSize, windowSize
+	if w < winW {
+		winW = w
+	}
+	if h < winH {
+		winH = h
+	}
+
+	type point struct{ x, y int }
+
+	var points []point
+	for y := 0; y+windowSize <= h; y += stride {
+		for x := 0; x+windowSize <= w; x += stride {
+			points = append(points, point{x, y})
+		}
+	}
+	if len(points) == 0 {
+		points = append(points, point{0, 0})
+	}
+
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(points) {
+		workers = len(points)
+	}
+
+	chunk := (len(points) + workers - 1) / workers
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		total float64
+	)
+
+	for start := 0; start < len(points); start += chunk {
+		end := start + chunk
+		if end > len(points) {
+			end = len(points)
+		}
+
+		wg.Add(1)
+		go func(points []point) {
+			defer wg.Done()
+
+			var sum float64
+			for _, p := range points {
+				sum += windowSSIM(a, b, p.x, p.y, winW, winH)
+			}
+
+			mu.Lock()
+			total += sum
+			mu.Unlock()
+		}(points[start:end])
+	}
+
+	wg.Wait()
+
+	return total / float64(len(points))
+}
+
+// windowSSIM scores the winW x winH window at (x, y). winW and winH are
+// normally windowSize, but shrink for images smaller than a full window
+// (see ssim).
+func windowSSIM(a, b lumaImage, x, y, winW, winH int) float64 {
+	n := float64(winW * winH)
+
+	var sumA, sumB float64
+	for j := 0; j < winH; j++ {
+		for i := 0; i < winW; i++ {
+			sumA += a.at(x+i, y+j)
+			sumB += b.at(x+i, y+j)
+		}
+	}
+	meanA := sumA / n
+	meanB := sumB / n
+
+	var varA, varB, covar float64
+	for j := 0; j < winH; j++ {
+		for i := 0; i < winW; i++ {
+			da := a.at(x+i, y+j) - meanA
+			db := b.at(x+i, y+j) - meanB
+			varA += da * da
+			varB += db * db
+			covar += da * db
+		}
+	}
+	// A 1xN or Nx1 window has only one degree of freedom to spend on the
+	// mean, leaving none for the sample variance; fall back to the
+	// population divisor rather than dividing by zero.
+	denom := n - 1
+	if denom < 1 {
+		denom = 1
+	}
+	varA /= denom
+	varB /= denom
+	covar /= denom
+
+	return ((2*meanA*meanB + c1) * (2*covar + c2)) /
+		((meanA*meanA + meanB*meanB + c1) * (varA + varB + c2))
+}
+
+// lumaImage is an 8-bit-range luma plane extracted from an image.Image,
+// kept as float64 so SSIM math doesn't re-convert on every window.
+type lumaImage struct {
+	pix  []float64
+	w, h int
+}
+
+func (l lumaImage) at(x, y int) float64 {
+	return l.pix[y*l.w+x]
+}
+
+// downsample halves the image using 2x2 box averaging, used by
+// CompareMultiScale to evaluate coarser scales.
+func (l lumaImage) downsample() lumaImage {
+	w, h := l.w/2, l.h/2
+	pix := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			pix[y*w+x] = (l.at(2*x, 2*y) + l.at(2*x+1, 2*y) + l.at(2*x, 2*y+1) + l.at(2*x+1, 2*y+1)) / 4
+		}
+	}
+	return lumaImage{pix: pix, w: w, h: h}
+}
+
+func luma(img image.Image) lumaImage {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	pix := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			pix[y*w+x] = 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(b>>8)
+		}
+	}
+	return lumaImage{pix: pix, w: w, h: h}
+}
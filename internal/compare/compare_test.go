@@ -0,0 +1,87 @@
+package compare
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"testing"
+)
+
+// checkerboard returns a w x h grayscale image alternating between 0 and
+// 255 every other pixel, giving Compare/CompareMultiScale real structure
+// to score instead of a flat field.
+func checkerboard(w, h int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if (x+y)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestCompareIdentical(t *testing.T) {
+	img := checkerboard(16, 16)
+	if got := Compare(img, img, 2); got > 1e-9 {
+		t.Fatalf("Compare(img, img, 2) = %v, want ~0", got)
+	}
+}
+
+func TestCompareSmallerThanWindow(t *testing.T) {
+	// windowSize is 8x8; a 4x4 image used to panic with "index out of
+	// range" because windowSSIM always indexed a full 8x8 window.
+	ref := checkerboard(4, 4)
+	test := checkerboard(4, 4)
+	if got := Compare(ref, test, 2); got > 1e-9 {
+		t.Fatalf("Compare(ref, test, 2) = %v, want ~0 for identical images", got)
+	}
+}
+
+func TestCompareOnePixel(t *testing.T) {
+	ref := checkerboard(1, 1)
+	test := image.NewGray(image.Rect(0, 0, 1, 1))
+	test.SetGray(0, 0, color.Gray{Y: 0})
+
+	if got := Compare(ref, ref, 2); got > 1e-9 {
+		t.Fatalf("Compare(ref, ref, 2) = %v, want ~0", got)
+	}
+	if got := Compare(ref, test, 2); got < 0 {
+		t.Fatalf("Compare(ref, test, 2) = %v, want >= 0 for differing 1x1 images", got)
+	}
+}
+
+func TestCompareMultiScaleSmallerThanWindow(t *testing.T) {
+	img := checkerboard(4, 4)
+	if got := CompareMultiScale(img, img, 2); got > 1e-9 {
+		t.Fatalf("CompareMultiScale(img, img, 2) = %v, want ~0", got)
+	}
+}
+
+func TestCompareDifferentImages(t *testing.T) {
+	ref := checkerboard(16, 16)
+	test := image.NewGray(image.Rect(0, 0, 16, 16)) // all black
+
+	if got := Compare(ref, test, 2); got <= 0 {
+		t.Fatalf("Compare(ref, test, 2) = %v, want > 0 for visibly different images", got)
+	}
+}
+
+// TestCompareConcurrent exercises independent Compare calls, each with a
+// different worker count, running at the same time. Workers used to be a
+// shared package variable mutated per call, which raced under concurrent
+// callers (e.g. smlr's batch mode); run with -race to catch a regression.
+func TestCompareConcurrent(t *testing.T) {
+	img := checkerboard(16, 16)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 4; i++ {
+		wg.Add(1)
+		go func(workers int) {
+			defer wg.Done()
+			Compare(img, img, workers)
+		}(i)
+	}
+	wg.Wait()
+}
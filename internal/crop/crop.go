@@ -0,0 +1,110 @@
+// Package crop picks the most visually important region of an image to
+// keep when it must be cut down to a smaller target size.
+package crop
+
+import (
+	"image"
+	"math"
+)
+
+// Smart returns the top-left corner of a w x h window within img that
+// maximizes total Sobel gradient-magnitude energy, so a forced crop keeps
+// the busiest (most detailed) region instead of an arbitrary corner.
+func Smart(img image.Image, w, h int) image.Point {
+
+	b := img.Bounds()
+	imgW, imgH := b.Dx(), b.Dy()
+
+	maxX := imgW - w
+	maxY := imgH - h
+	if maxX <= 0 && maxY <= 0 {
+		return image.Pt(0, 0)
+	}
+	if maxX < 0 {
+		maxX = 0
+	}
+	if maxY < 0 {
+		maxY = 0
+	}
+
+	sat := integralImage(sobelEnergy(img), imgW, imgH)
+
+	best := image.Pt(0, 0)
+	bestSum := -1.0
+
+	for y := 0; y <= maxY; y++ {
+		for x := 0; x <= maxX; x++ {
+			if sum := windowSum(sat, imgW, x, y, w, h); sum > bestSum {
+				bestSum = sum
+				best = image.Pt(x, y)
+			}
+		}
+	}
+
+	return best
+
+}
+
+// sobelEnergy returns the Sobel gradient magnitude of img's luma channel,
+// one float64 per pixel, row-major, clamping at the image edges.
+func sobelEnergy(img image.Image) []float64 {
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	luma := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			luma[y*w+x] = 0.299*float64(r>>8) + 0.587*float64(g>>8) + 0.114*float64(bl>>8)
+		}
+	}
+
+	at := func(x, y int) float64 {
+		if x < 0 {
+			x = 0
+		} else if x >= w {
+			x = w - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= h {
+			y = h - 1
+		}
+		return luma[y*w+x]
+	}
+
+	energy := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			gx := (at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1)) -
+				(at(x-1, y-1) + 2*at(x-1, y) + at(x-1, y+1))
+			gy := (at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1)) -
+				(at(x-1, y-1) + 2*at(x, y-1) + at(x+1, y-1))
+			energy[y*w+x] = math.Hypot(gx, gy)
+		}
+	}
+
+	return energy
+
+}
+
+// integralImage builds a summed-area table (with a leading zero row and
+// column) over values so windowSum can compute any window's total in
+// constant time.
+func integralImage(values []float64, w, h int) []float64 {
+	stride := w + 1
+	sat := make([]float64, stride*(h+1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sat[(y+1)*stride+(x+1)] = values[y*w+x] + sat[y*stride+(x+1)] + sat[(y+1)*stride+x] - sat[y*stride+x]
+		}
+	}
+	return sat
+}
+
+func windowSum(sat []float64, w, x, y, winW, winH int) float64 {
+	stride := w + 1
+	x2, y2 := x+winW, y+winH
+	return sat[y2*stride+x2] - sat[y*stride+x2] - sat[y2*stride+x] + sat[y*stride+x]
+}
@@ -0,0 +1,55 @@
+package crop
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// flatWithSquare returns a w x h grayscale image that's flat black except
+// for a solid white sx x sy square at (ox, oy), so Smart has a single
+// unambiguous busiest region to find. A solid block (rather than a
+// high-frequency stripe pattern) avoids aliasing against the Sobel
+// kernel, which can't see edges that repeat every other pixel.
+func flatWithSquare(w, h, ox, oy, sx, sy int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := oy; y < oy+sy; y++ {
+		for x := ox; x < ox+sx; x++ {
+			img.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+	return img
+}
+
+func TestSmartPicksBusiestRegion(t *testing.T) {
+	img := flatWithSquare(20, 10, 12, 2, 6, 6)
+
+	got := Smart(img, 8, 8)
+	if got.X < 10 {
+		t.Fatalf("Smart() = %v, want a window over the square at x=12, x >= 10", got)
+	}
+}
+
+func TestSmartWindowIsWholeImage(t *testing.T) {
+	img := flatWithSquare(20, 10, 12, 2, 6, 6)
+
+	if got := Smart(img, 20, 10); got != (image.Point{}) {
+		t.Fatalf("Smart() = %v, want {0,0} when the window covers the whole image", got)
+	}
+}
+
+func TestSmartWindowLargerThanImage(t *testing.T) {
+	img := flatWithSquare(20, 10, 12, 2, 6, 6)
+
+	if got := Smart(img, 30, 30); got != (image.Point{}) {
+		t.Fatalf("Smart() = %v, want {0,0} when the window exceeds the image", got)
+	}
+}
+
+func TestSmartFlatImageDefaultsToOrigin(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 16, 16))
+
+	if got := Smart(img, 8, 8); got != (image.Point{}) {
+		t.Fatalf("Smart() = %v, want {0,0} for a flat image with no standout region", got)
+	}
+}
@@ -0,0 +1,158 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+const orientationTag = 0x0112
+
+// Orientation reads the EXIF Orientation tag (1-8, per the TIFF/EXIF
+// spec) from a raw EXIF APP1 segment payload (including the "Exif\0\0"
+// header). It returns 1, the default/normal orientation, if the payload
+// is absent, malformed, or has no Orientation tag.
+func Orientation(payload []byte) int {
+	v, err := readOrientation(payload)
+	if err != nil || v == 0 {
+		return 1
+	}
+	return v
+}
+
+// ClearOrientation returns a copy of payload with the Orientation tag's
+// value rewritten to 1 (normal), for callers that already applied the
+// corresponding rotation/flip to the pixels and don't want a downstream
+// viewer to apply it again. Payloads without an Orientation tag, or that
+// fail to parse, are returned unchanged.
+func ClearOrientation(payload []byte) []byte {
+
+	out := append([]byte(nil), payload...)
+
+	tiff, order, err := tiffHeader(out)
+	if err != nil {
+		return out
+	}
+
+	ifd0, err := firstIFDOffset(tiff, order)
+	if err != nil {
+		return out
+	}
+
+	entries, pos, err := ifdEntries(tiff, order, ifd0)
+	if err != nil {
+		return out
+	}
+
+	for i, e := range entries {
+		if e.tag == orientationTag {
+			start := pos + i*12
+			order.PutUint16(tiff[start+8:start+10], 1)
+			break
+		}
+	}
+
+	return out
+
+}
+
+func readOrientation(payload []byte) (int, error) {
+
+	tiff, order, err := tiffHeader(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	ifd0, err := firstIFDOffset(tiff, order)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, _, err := ifdEntries(tiff, order, ifd0)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, e := range entries {
+		if e.tag == orientationTag {
+			return int(order.Uint16(e.value[:2])), nil
+		}
+	}
+
+	return 0, nil
+
+}
+
+// tiffHeader strips the "Exif\0\0" prefix from payload and validates the
+// TIFF header that follows, returning the TIFF blob (offsets within it
+// are relative to its own start, per the TIFF spec) and its byte order.
+func tiffHeader(payload []byte) ([]byte, binary.ByteOrder, error) {
+
+	if len(payload) < len(exifPrefix)+8 || !bytes.Equal(payload[:len(exifPrefix)], exifPrefix) {
+		return nil, nil, errors.New("metadata: not an EXIF APP1 segment")
+	}
+
+	tiff := payload[len(exifPrefix):]
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, errors.New("metadata: bad TIFF byte order marker")
+	}
+
+	if order.Uint16(tiff[2:4]) != 42 {
+		return nil, nil, errors.New("metadata: bad TIFF magic number")
+	}
+
+	return tiff, order, nil
+
+}
+
+func firstIFDOffset(tiff []byte, order binary.ByteOrder) (uint32, error) {
+	if len(tiff) < 8 {
+		return 0, errors.New("metadata: truncated TIFF header")
+	}
+	return order.Uint32(tiff[4:8]), nil
+}
+
+type ifdEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value [4]byte
+}
+
+// ifdEntries parses the IFD at offset within tiff, returning its entries
+// and the byte position of the entry table (so callers can patch a field
+// in place).
+func ifdEntries(tiff []byte, order binary.ByteOrder, offset uint32) ([]ifdEntry, int, error) {
+
+	if int(offset)+2 > len(tiff) {
+		return nil, 0, errors.New("metadata: IFD offset out of range")
+	}
+
+	n := int(order.Uint16(tiff[offset : offset+2]))
+	pos := int(offset) + 2
+
+	entries := make([]ifdEntry, 0, n)
+	for i := 0; i < n; i++ {
+		start := pos + i*12
+		if start+12 > len(tiff) {
+			return nil, 0, errors.New("metadata: truncated IFD entry")
+		}
+		var e ifdEntry
+		e.tag = order.Uint16(tiff[start : start+2])
+		e.typ = order.Uint16(tiff[start+2 : start+4])
+		e.count = order.Uint32(tiff[start+4 : start+8])
+		copy(e.value[:], tiff[start+8:start+12])
+		entries = append(entries, e)
+	}
+
+	return entries, pos, nil
+
+}
+
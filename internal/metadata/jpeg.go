@@ -0,0 +1,158 @@
+// Package metadata extracts APPn segments (EXIF, ICC profile, XMP,
+// Photoshop IRB, Adobe color transform) from a source JPEG and PNG text
+// and color-profile chunks from a source PNG, so callers can splice them
+// back into a re-encoded output that would otherwise lose them.
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// JPEG APPn markers that can carry metadata worth preserving.
+const (
+	markerAPP0  = 0xE0
+	markerAPP1  = 0xE1
+	markerAPP2  = 0xE2
+	markerAPP13 = 0xED
+	markerAPP14 = 0xEE
+
+	markerSOS = 0xDA
+	markerEOI = 0xD9
+)
+
+var (
+	exifPrefix = []byte("Exif\x00\x00")
+	xmpPrefix  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+	iccPrefix  = []byte("ICC_PROFILE\x00")
+)
+
+// Segment is a raw JPEG APPn marker segment, as found in the source file.
+type Segment struct {
+	Marker byte
+	Data   []byte
+}
+
+// IsEXIF reports whether the segment carries an EXIF TIFF payload.
+func (s Segment) IsEXIF() bool {
+	return s.Marker == markerAPP1 && bytes.HasPrefix(s.Data, exifPrefix)
+}
+
+// ICCSegment builds the APP2 segment JPEG uses to carry an ICC color
+// profile, for callers translating a profile recovered from elsewhere
+// (e.g. a PNG iCCP chunk) into a JPEG output.
+func ICCSegment(profile []byte) Segment {
+	data := make([]byte, 0, len(iccPrefix)+len(profile))
+	data = append(data, iccPrefix...)
+	data = append(data, profile...)
+	return Segment{Marker: markerAPP2, Data: data}
+}
+
+// IsJPEG reports whether raw begins with a JPEG SOI marker.
+func IsJPEG(raw []byte) bool {
+	return len(raw) >= 2 && raw[0] == 0xFF && raw[1] == 0xD8
+}
+
+// ExtractJPEG scans raw JPEG bytes for APP0/APP1/APP2/APP13/APP14
+// segments carrying EXIF, XMP, ICC profile, Photoshop IRB, or Adobe
+// color-transform data, and returns them in file order. Scanning stops at
+// the first SOS (start of scan) marker, since metadata segments always
+// precede the entropy-coded image data.
+func ExtractJPEG(raw []byte) ([]Segment, error) {
+
+	if len(raw) < 4 || raw[0] != 0xFF || raw[1] != 0xD8 {
+		return nil, errors.New("metadata: not a JPEG (missing SOI)")
+	}
+
+	var segs []Segment
+
+	pos := 2
+	for pos+2 <= len(raw) {
+
+		if raw[pos] != 0xFF {
+			return nil, fmt.Errorf("metadata: expected marker at offset %d", pos)
+		}
+		marker := raw[pos+1]
+		pos += 2
+
+		// Markers with no payload: TEM, RSTn, and EOI.
+		if marker == 0x01 || (marker >= 0xD0 && marker <= markerEOI) {
+			if marker == markerEOI {
+				break
+			}
+			continue
+		}
+
+		if marker == markerSOS {
+			break
+		}
+
+		if pos+2 > len(raw) {
+			return nil, fmt.Errorf("metadata: truncated segment at offset %d", pos)
+		}
+		length := int(raw[pos])<<8 | int(raw[pos+1])
+		if length < 2 || pos+length > len(raw) {
+			return nil, fmt.Errorf("metadata: segment at offset %d overruns data", pos)
+		}
+		payload := raw[pos+2 : pos+length]
+
+		if isMetadataSegment(marker, payload) {
+			segs = append(segs, Segment{Marker: marker, Data: append([]byte(nil), payload...)})
+		}
+
+		pos += length
+
+	}
+
+	return segs, nil
+
+}
+
+func isMetadataSegment(marker byte, payload []byte) bool {
+	switch marker {
+	case markerAPP0:
+		return true
+	case markerAPP1:
+		return bytes.HasPrefix(payload, exifPrefix) || bytes.HasPrefix(payload, xmpPrefix)
+	case markerAPP2:
+		return bytes.HasPrefix(payload, iccPrefix)
+	case markerAPP13, markerAPP14:
+		return true
+	default:
+		return false
+	}
+}
+
+// SpliceJPEG returns a copy of encoded with segs inserted immediately
+// after the SOI marker, in order, rewriting each segment's length field.
+// It's the inverse of ExtractJPEG.
+func SpliceJPEG(encoded []byte, segs []Segment) ([]byte, error) {
+
+	if len(segs) == 0 {
+		return encoded, nil
+	}
+	if len(encoded) < 2 || encoded[0] != 0xFF || encoded[1] != 0xD8 {
+		return nil, errors.New("metadata: not a JPEG (missing SOI)")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encoded[:2])
+
+	for _, seg := range segs {
+		length := len(seg.Data) + 2
+		if length > 0xFFFF {
+			return nil, fmt.Errorf("metadata: segment too large to splice (%d bytes)", length)
+		}
+		buf.WriteByte(0xFF)
+		buf.WriteByte(seg.Marker)
+		buf.WriteByte(byte(length >> 8))
+		buf.WriteByte(byte(length))
+		buf.Write(seg.Data)
+	}
+
+	buf.Write(encoded[2:])
+
+	return buf.Bytes(), nil
+
+}
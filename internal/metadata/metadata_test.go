@@ -0,0 +1,133 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"testing"
+)
+
+// exifFixture builds a minimal little-endian TIFF/EXIF blob with an
+// Orientation tag and a GPSInfo IFD pointer tag, so tests can exercise
+// Orientation/ClearOrientation without a real camera file.
+func exifFixture(orientation uint16, gpsOffset uint32) []byte {
+
+	var tiff bytes.Buffer
+	tiff.WriteString("II")
+	binary.Write(&tiff, binary.LittleEndian, uint16(42))
+	binary.Write(&tiff, binary.LittleEndian, uint32(8)) // IFD0 at offset 8
+
+	binary.Write(&tiff, binary.LittleEndian, uint16(2)) // 2 entries
+
+	// Orientation: tag 0x0112, type SHORT (3), count 1.
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.LittleEndian, uint16(3))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, orientation)
+	binary.Write(&tiff, binary.LittleEndian, uint16(0)) // padding to fill the 4-byte value field
+
+	// GPSInfo IFD pointer: tag 0x8825, type LONG (4), count 1.
+	binary.Write(&tiff, binary.LittleEndian, uint16(0x8825))
+	binary.Write(&tiff, binary.LittleEndian, uint16(4))
+	binary.Write(&tiff, binary.LittleEndian, uint32(1))
+	binary.Write(&tiff, binary.LittleEndian, gpsOffset)
+
+	binary.Write(&tiff, binary.LittleEndian, uint32(0)) // no next IFD
+
+	return append(append([]byte{}, exifPrefix...), tiff.Bytes()...)
+
+}
+
+func jpegFixture(segs ...Segment) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8})
+	for _, s := range segs {
+		length := len(s.Data) + 2
+		buf.Write([]byte{0xFF, s.Marker, byte(length >> 8), byte(length)})
+		buf.Write(s.Data)
+	}
+	buf.Write([]byte{0xFF, 0xD9})
+	return buf.Bytes()
+}
+
+func TestExtractJPEGRoundTrip(t *testing.T) {
+
+	exif := exifFixture(6, 200)
+	icc := Segment{Marker: markerAPP2, Data: append(append([]byte{}, iccPrefix...), []byte("fake-icc-profile")...)}
+
+	src := jpegFixture(Segment{Marker: markerAPP1, Data: exif}, icc)
+
+	segs, err := ExtractJPEG(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(segs) != 2 {
+		t.Fatalf("got %d segments, want 2", len(segs))
+	}
+	if !segs[0].IsEXIF() {
+		t.Fatal("first segment should be EXIF")
+	}
+	if got := Orientation(segs[0].Data); got != 6 {
+		t.Fatalf("Orientation() = %d, want 6", got)
+	}
+
+	// Splicing into a bare encode and re-extracting should reproduce the
+	// same segments, including the GPSInfo tag buried in the EXIF blob.
+	bare := jpegFixture()
+	spliced, err := SpliceJPEG(bare, segs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roundTripped, err := ExtractJPEG(spliced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roundTripped) != 2 || !bytes.Equal(roundTripped[0].Data, exif) || !bytes.Equal(roundTripped[1].Data, icc.Data) {
+		t.Fatalf("round-tripped segments don't match source")
+	}
+
+}
+
+func TestClearOrientation(t *testing.T) {
+
+	exif := exifFixture(6, 0)
+
+	cleared := ClearOrientation(exif)
+	if got := Orientation(cleared); got != 1 {
+		t.Fatalf("Orientation() after clearing = %d, want 1", got)
+	}
+
+	// The original payload must be untouched.
+	if got := Orientation(exif); got != 6 {
+		t.Fatalf("ClearOrientation mutated its input: Orientation() = %d, want 6", got)
+	}
+
+}
+
+func TestICCFromPNGRoundTrip(t *testing.T) {
+
+	profile := []byte("a fake ICC profile, just long enough to compress")
+
+	var compressed bytes.Buffer
+	w := zlib.NewWriter(&compressed)
+	w.Write(profile)
+	w.Close()
+
+	var chunkData bytes.Buffer
+	chunkData.WriteString("icc")
+	chunkData.WriteByte(0) // profile name terminator
+	chunkData.WriteByte(0) // compression method: zlib
+	chunkData.Write(compressed.Bytes())
+
+	chunks := []PNGChunk{{Type: "iCCP", Data: chunkData.Bytes()}}
+
+	got, ok := ICCFromPNG(chunks)
+	if !ok {
+		t.Fatal("ICCFromPNG didn't find the profile")
+	}
+	if !bytes.Equal(got, profile) {
+		t.Fatalf("ICCFromPNG() = %q, want %q", got, profile)
+	}
+
+}
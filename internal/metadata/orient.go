@@ -0,0 +1,79 @@
+package metadata
+
+import "image"
+
+// Apply returns img transformed per EXIF orientation values 1-8: 1 is a
+// no-op, 2-4 are horizontal/180/vertical flips, and 5-8 combine a flip
+// with a 90-degree rotation. Unknown values are treated as 1.
+func Apply(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return flipH(img)
+	case 3:
+		return rotate180(img)
+	case 4:
+		return flipV(img)
+	case 5:
+		return rotate90(flipV(img))
+	case 6:
+		return rotate90(img)
+	case 7:
+		return rotate90(flipH(img))
+	case 8:
+		return rotate270(img)
+	default:
+		return img
+	}
+}
+
+func flipH(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(b.Max.X-1-(x-b.Min.X), y, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func flipV(img image.Image) image.Image {
+	b := img.Bounds()
+	out := image.NewNRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(x, b.Max.Y-1-(y-b.Min.Y), img.At(x, y))
+		}
+	}
+	return out
+}
+
+func rotate180(img image.Image) image.Image {
+	return flipV(flipH(img))
+}
+
+// rotate90 rotates img 90 degrees clockwise.
+func rotate90(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(h-1-(y-b.Min.Y), x-b.Min.X, img.At(x, y))
+		}
+	}
+	return out
+}
+
+// rotate270 rotates img 90 degrees counter-clockwise (= 270 clockwise).
+func rotate270(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	out := image.NewNRGBA(image.Rect(0, 0, h, w))
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(y-b.Min.Y, w-1-(x-b.Min.X), img.At(x, y))
+		}
+	}
+	return out
+}
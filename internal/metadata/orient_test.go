@@ -0,0 +1,72 @@
+package metadata
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// cornerImage returns a 2x3 NRGBA image with a distinct color in each
+// corner, so Apply's output can be checked by tracking where each corner
+// ends up instead of comparing whole images.
+func cornerImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 2, 3))
+	img.Set(0, 0, color.NRGBA{255, 0, 0, 255})   // top-left: red
+	img.Set(1, 0, color.NRGBA{0, 255, 0, 255})   // top-right: green
+	img.Set(0, 2, color.NRGBA{0, 0, 255, 255})   // bottom-left: blue
+	img.Set(1, 2, color.NRGBA{255, 255, 0, 255}) // bottom-right: yellow
+	return img
+}
+
+func at(img image.Image, x, y int) color.NRGBA {
+	r, g, b, a := img.At(x, y).RGBA()
+	return color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+}
+
+var (
+	red    = color.NRGBA{255, 0, 0, 255}
+	green  = color.NRGBA{0, 255, 0, 255}
+	blue   = color.NRGBA{0, 0, 255, 255}
+	yellow = color.NRGBA{255, 255, 0, 255}
+)
+
+func TestApplyOrientationCorners(t *testing.T) {
+
+	// Expected corner layout per orientation, read as (top-left, top-right,
+	// bottom-left, bottom-right) of the *output*. Orientations 5-8 also
+	// transpose width/height (source is 2x3, output is 3x2).
+	cases := []struct {
+		orientation    int
+		w, h           int
+		tl, tr, bl, br color.NRGBA
+	}{
+		{1, 2, 3, red, green, blue, yellow}, // no-op
+		{2, 2, 3, green, red, yellow, blue}, // mirror horizontal
+		{3, 2, 3, yellow, blue, green, red}, // rotate 180
+		{4, 2, 3, blue, yellow, red, green}, // mirror vertical
+		{5, 3, 2, red, blue, green, yellow}, // transpose (mirror + rotate270)
+		{6, 3, 2, blue, red, yellow, green}, // rotate 90 CW
+		{7, 3, 2, yellow, green, blue, red}, // transverse (mirror + rotate90)
+		{8, 3, 2, green, yellow, red, blue}, // rotate 270 CW
+	}
+
+	for _, c := range cases {
+		out := Apply(cornerImage(), c.orientation)
+		b := out.Bounds()
+		if b.Dx() != c.w || b.Dy() != c.h {
+			t.Fatalf("orientation %d: size = %dx%d, want %dx%d", c.orientation, b.Dx(), b.Dy(), c.w, c.h)
+		}
+		if got := at(out, b.Min.X, b.Min.Y); got != c.tl {
+			t.Fatalf("orientation %d: top-left = %v, want %v", c.orientation, got, c.tl)
+		}
+		if got := at(out, b.Max.X-1, b.Min.Y); got != c.tr {
+			t.Fatalf("orientation %d: top-right = %v, want %v", c.orientation, got, c.tr)
+		}
+		if got := at(out, b.Min.X, b.Max.Y-1); got != c.bl {
+			t.Fatalf("orientation %d: bottom-left = %v, want %v", c.orientation, got, c.bl)
+		}
+		if got := at(out, b.Max.X-1, b.Max.Y-1); got != c.br {
+			t.Fatalf("orientation %d: bottom-right = %v, want %v", c.orientation, got, c.br)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io/ioutil"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// IsPNG reports whether raw begins with the PNG signature.
+func IsPNG(raw []byte) bool {
+	return bytes.HasPrefix(raw, pngSignature)
+}
+
+// PNGChunk is a raw PNG chunk (type and data, without its length/CRC).
+type PNGChunk struct {
+	Type string
+	Data []byte
+}
+
+// ExtractPNG scans raw PNG bytes for tEXt, iTXt, and iCCP chunks and
+// returns them in file order.
+func ExtractPNG(raw []byte) ([]PNGChunk, error) {
+
+	if len(raw) < 8 || !bytes.Equal(raw[:8], pngSignature) {
+		return nil, errors.New("metadata: not a PNG (bad signature)")
+	}
+
+	var chunks []PNGChunk
+
+	pos := 8
+	for pos+8 <= len(raw) {
+
+		length := int(binary.BigEndian.Uint32(raw[pos : pos+4]))
+		typ := string(raw[pos+4 : pos+8])
+		dataStart := pos + 8
+		if dataStart+length+4 > len(raw) {
+			return nil, errors.New("metadata: truncated PNG chunk")
+		}
+		data := raw[dataStart : dataStart+length]
+
+		switch typ {
+		case "tEXt", "iTXt", "iCCP":
+			chunks = append(chunks, PNGChunk{Type: typ, Data: append([]byte(nil), data...)})
+		}
+
+		pos = dataStart + length + 4 // skip CRC
+		if typ == "IEND" {
+			break
+		}
+
+	}
+
+	return chunks, nil
+
+}
+
+// InjectPNG returns a copy of encoded PNG bytes with chunks spliced in
+// immediately after the IHDR chunk, in order. It's the inverse of the
+// tEXt/iTXt/iCCP chunks ExtractPNG returns.
+func InjectPNG(encoded []byte, chunks []PNGChunk) ([]byte, error) {
+
+	if len(chunks) == 0 {
+		return encoded, nil
+	}
+	if len(encoded) < 8 || !bytes.Equal(encoded[:8], pngSignature) {
+		return nil, errors.New("metadata: not a PNG (bad signature)")
+	}
+
+	ihdrLength := int(binary.BigEndian.Uint32(encoded[8:12]))
+	ihdrEnd := 8 + 8 + ihdrLength + 4
+	if ihdrEnd > len(encoded) {
+		return nil, errors.New("metadata: truncated IHDR chunk")
+	}
+
+	var buf bytes.Buffer
+	buf.Write(encoded[:ihdrEnd])
+	for _, c := range chunks {
+		writePNGChunk(&buf, c.Type, c.Data)
+	}
+	buf.Write(encoded[ihdrEnd:])
+
+	return buf.Bytes(), nil
+
+}
+
+func writePNGChunk(buf *bytes.Buffer, typ string, data []byte) {
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(data)))
+	buf.Write(length[:])
+
+	buf.WriteString(typ)
+	buf.Write(data)
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	buf.Write(sum[:])
+
+}
+
+// ICCFromPNG extracts and zlib-decompresses the ICC profile carried in an
+// iCCP chunk, if one is present among chunks.
+func ICCFromPNG(chunks []PNGChunk) ([]byte, bool) {
+
+	for _, c := range chunks {
+		if c.Type != "iCCP" {
+			continue
+		}
+
+		nul := bytes.IndexByte(c.Data, 0)
+		if nul < 0 || nul+2 > len(c.Data) {
+			continue
+		}
+		// c.Data[nul] is the profile-name terminator; c.Data[nul+1] is the
+		// compression method, always 0 (zlib) per the PNG spec.
+
+		r, err := zlib.NewReader(bytes.NewReader(c.Data[nul+2:]))
+		if err != nil {
+			continue
+		}
+		profile, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			continue
+		}
+
+		return profile, true
+	}
+
+	return nil, false
+
+}
@@ -0,0 +1,135 @@
+package smlr
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/chai2010/webp"
+	_ "golang.org/x/image/webp" // registers image.Decode support for WebP
+)
+
+const (
+	PNG Format = iota + 1
+	WebP
+	AVIF
+)
+
+func (f Format) String() string {
+	switch f {
+	case JPEG:
+		return "jpeg"
+	case PNG:
+		return "png"
+	case WebP:
+		return "webp"
+	case AVIF:
+		return "avif"
+	default:
+		return "unknown"
+	}
+}
+
+// Encoder encodes an image at a given quality level. Quality's meaning and
+// valid range are encoder-specific; see QualityRange.
+type Encoder interface {
+	Encode(w io.Writer, img image.Image, quality int) error
+
+	// QualityRange returns the inclusive [min, max] karySearch should probe
+	// between, with max always producing the most faithful encode.
+	QualityRange() (min, max int)
+}
+
+// EncoderFor returns the built-in Encoder for f.
+func EncoderFor(f Format) (Encoder, error) {
+	switch f {
+	case JPEG:
+		return jpegEncoder{}, nil
+	case PNG:
+		return pngEncoder{}, nil
+	case WebP:
+		return webpEncoder{}, nil
+	case AVIF:
+		return newAVIFEncoder()
+	default:
+		return nil, errUnsupportedFormat(f)
+	}
+}
+
+type jpegEncoder struct{}
+
+func (jpegEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
+
+func (jpegEncoder) QualityRange() (min, max int) { return 1, 100 }
+
+type webpEncoder struct{}
+
+func (webpEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	return webp.Encode(w, img, &webp.Options{Quality: float32(quality)})
+}
+
+func (webpEncoder) QualityRange() (min, max int) { return 1, 100 }
+
+// pngEncoder searches zlib compression level (0-9) rather than a lossy
+// quality, so its encodes are always pixel-identical to the source; see
+// Optimize's handling of losslessEncoder.
+type pngEncoder struct{}
+
+func (pngEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	enc := png.Encoder{CompressionLevel: pngCompressionLevel(quality)}
+	return enc.Encode(w, img)
+}
+
+func (pngEncoder) QualityRange() (min, max int) { return 0, 9 }
+
+func (pngEncoder) lossless() {}
+
+func pngCompressionLevel(level int) png.CompressionLevel {
+	switch {
+	case level <= 0:
+		return png.NoCompression
+	case level >= 9:
+		return png.BestCompression
+	case level <= 3:
+		return png.BestSpeed
+	default:
+		return png.DefaultCompression
+	}
+}
+
+// losslessEncoder marks an Encoder whose quality parameter only trades
+// encode time/output size for no change in pixel fidelity (e.g. PNG's
+// zlib level), so Optimize skips the deviation search and just encodes at
+// the most thorough setting.
+type losslessEncoder interface {
+	Encoder
+	lossless()
+}
+
+// unverifiableEncoder marks an Encoder whose output format Go can't
+// decode back, so Optimize has no way to score a candidate against the
+// source and, like losslessEncoder, skips the deviation search and just
+// encodes at the encoder's maximum QualityRange value.
+type unverifiableEncoder interface {
+	Encoder
+	unverifiable()
+}
+
+// skipsSearch reports whether Optimize should bisect enc's quality range
+// at all: false for encoders it can't verify (unverifiableEncoder) or
+// doesn't need to (losslessEncoder).
+func skipsSearch(enc Encoder) bool {
+	if _, ok := enc.(losslessEncoder); ok {
+		return true
+	}
+	_, ok := enc.(unverifiableEncoder)
+	return ok
+}
+
+func errUnsupportedFormat(f Format) error {
+	return fmt.Errorf("smlr: unsupported format %v", f)
+}
@@ -0,0 +1,34 @@
+//go:build avif
+
+package smlr
+
+import (
+	"image"
+	"io"
+
+	avif "github.com/Kagami/go-avif"
+)
+
+// newAVIFEncoder builds the real AVIF encoder. It's only compiled with
+// -tags avif, since github.com/Kagami/go-avif cgo's against the system
+// libaom encoder library, which isn't vendored; see encoder_avif_stub.go
+// for the default build's fallback.
+func newAVIFEncoder() (Encoder, error) {
+	return avifEncoder{}, nil
+}
+
+// avifEncoder is encode-only: github.com/Kagami/go-avif doesn't ship a
+// decoder, and nothing registers image.Decode support for AVIF (contrast
+// webpEncoder, backed by golang.org/x/image/webp). Optimize can't
+// re-decode a candidate to score it, so avifEncoder implements
+// unverifiableEncoder and Optimize encodes once at max quality instead of
+// searching.
+type avifEncoder struct{}
+
+func (avifEncoder) Encode(w io.Writer, img image.Image, quality int) error {
+	return avif.Encode(w, img, &avif.Options{Quality: quality})
+}
+
+func (avifEncoder) QualityRange() (min, max int) { return 1, 100 }
+
+func (avifEncoder) unverifiable() {}
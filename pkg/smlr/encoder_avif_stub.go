@@ -0,0 +1,14 @@
+//go:build !avif
+
+package smlr
+
+import "fmt"
+
+// newAVIFEncoder is the default build's stand-in for encoder_avif.go's
+// real implementation. github.com/Kagami/go-avif cgo's against the
+// system libaom encoder library (not vendored), so AVIF support is gated
+// behind the "avif" build tag instead of shipping in every build; build
+// with -tags avif (and libaom-dev installed) to enable it.
+func newAVIFEncoder() (Encoder, error) {
+	return nil, fmt.Errorf("smlr: AVIF support requires building with -tags avif (and libaom-dev installed)")
+}
@@ -0,0 +1,131 @@
+package smlr
+
+import (
+	"image"
+
+	"github.com/jasonmoo/smlr/internal/crop"
+	"github.com/nfnt/resize"
+)
+
+// Fit controls how an image is resized when both Options.Width and
+// Options.Height are set. It's ignored when only one is set, since
+// there's no target box to fit or fill.
+type Fit int
+
+const (
+	// FitScale resizes to exactly Width x Height, same as resize.Resize:
+	// it distorts the aspect ratio if the target box doesn't match the
+	// source's. This is smlr's original behavior.
+	FitScale Fit = iota
+
+	// FitContain resizes so the whole image fits within Width x Height,
+	// preserving aspect ratio; the result is smaller than the target box
+	// on whichever axis has slack.
+	FitContain
+
+	// FitFill resizes so the image covers Width x Height, preserving
+	// aspect ratio, then crops the overflow per Anchor.
+	FitFill
+)
+
+// Anchor selects which part of an oversized image FitFill keeps after
+// the covering resize.
+type Anchor int
+
+const (
+	AnchorCenter Anchor = iota
+	AnchorTop
+	AnchorTopLeft
+
+	// AnchorSmart crops around the region with the most Sobel edge
+	// energy (see internal/crop), so detail-heavy content isn't cut off
+	// arbitrarily.
+	AnchorSmart
+)
+
+// resizeTo applies opts.Fit (and, for FitFill, opts.Anchor) to size img
+// for Options.Width/Height.
+func resizeTo(img image.Image, opts Options) image.Image {
+
+	w, h := opts.Width, opts.Height
+
+	if w == 0 || h == 0 {
+		return resize.Resize(w, h, img, opts.Resample)
+	}
+
+	switch opts.Fit {
+	case FitContain:
+		return resizeContain(img, w, h, opts.Resample)
+	case FitFill:
+		return resizeFill(img, w, h, opts.Anchor, opts.Resample)
+	default:
+		return resize.Resize(w, h, img, opts.Resample)
+	}
+
+}
+
+func resizeContain(img image.Image, w, h uint, resample resize.InterpolationFunction) image.Image {
+	scale := containScale(img.Bounds(), w, h)
+	cw, ch := scaledSize(img.Bounds(), scale)
+	return resize.Resize(cw, ch, img, resample)
+}
+
+func resizeFill(img image.Image, w, h uint, anchor Anchor, resample resize.InterpolationFunction) image.Image {
+
+	scale := fillScale(img.Bounds(), w, h)
+	coverW, coverH := scaledSize(img.Bounds(), scale)
+	covered := resize.Resize(coverW, coverH, img, resample)
+
+	var origin image.Point
+	switch anchor {
+	case AnchorTop:
+		origin = image.Pt(int(coverW-w)/2, 0)
+	case AnchorTopLeft:
+		origin = image.Pt(0, 0)
+	case AnchorSmart:
+		origin = crop.Smart(covered, int(w), int(h))
+	default: // AnchorCenter
+		origin = image.Pt(int(coverW-w)/2, int(coverH-h)/2)
+	}
+
+	return cropImage(covered, origin, int(w), int(h))
+
+}
+
+// containScale returns the largest scale factor that keeps bounds within
+// a w x h box.
+func containScale(bounds image.Rectangle, w, h uint) float64 {
+	scale := float64(w) / float64(bounds.Dx())
+	if s := float64(h) / float64(bounds.Dy()); s < scale {
+		scale = s
+	}
+	return scale
+}
+
+// fillScale returns the smallest scale factor that makes bounds cover a
+// w x h box.
+func fillScale(bounds image.Rectangle, w, h uint) float64 {
+	scale := float64(w) / float64(bounds.Dx())
+	if s := float64(h) / float64(bounds.Dy()); s > scale {
+		scale = s
+	}
+	return scale
+}
+
+func scaledSize(bounds image.Rectangle, scale float64) (uint, uint) {
+	return uint(float64(bounds.Dx())*scale + 0.5), uint(float64(bounds.Dy())*scale + 0.5)
+}
+
+// cropImage returns the w x h region of img starting at origin (relative
+// to img's bounds) as a new image.
+func cropImage(img image.Image, origin image.Point, w, h int) image.Image {
+	b := img.Bounds()
+	srcX, srcY := b.Min.X+origin.X, b.Min.Y+origin.Y
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			out.Set(x, y, img.At(srcX+x, srcY+y))
+		}
+	}
+	return out
+}
@@ -0,0 +1,93 @@
+package smlr
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/nfnt/resize"
+)
+
+func solidImage(w, h int, c color.Gray) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestResizeToScaleDistorts(t *testing.T) {
+	src := solidImage(200, 100, color.Gray{Y: 128})
+
+	out := resizeTo(src, Options{Width: 50, Height: 50, Fit: FitScale, Resample: resize.NearestNeighbor})
+	b := out.Bounds()
+	if b.Dx() != 50 || b.Dy() != 50 {
+		t.Fatalf("resizeTo(FitScale) size = %dx%d, want 50x50", b.Dx(), b.Dy())
+	}
+}
+
+func TestResizeToContainPreservesAspect(t *testing.T) {
+	src := solidImage(200, 100, color.Gray{Y: 128}) // 2:1
+
+	out := resizeTo(src, Options{Width: 50, Height: 50, Fit: FitContain, Resample: resize.NearestNeighbor})
+	b := out.Bounds()
+
+	// Width-limited: 50/200 = 0.25 scale, so height should come out to 25,
+	// leaving the box's height unfilled rather than cropping or distorting.
+	if b.Dx() != 50 || b.Dy() != 25 {
+		t.Fatalf("resizeTo(FitContain) size = %dx%d, want 50x25", b.Dx(), b.Dy())
+	}
+}
+
+func TestResizeToFillCoversTarget(t *testing.T) {
+	src := solidImage(200, 100, color.Gray{Y: 128}) // 2:1
+
+	out := resizeTo(src, Options{Width: 50, Height: 50, Fit: FitFill, Anchor: AnchorCenter, Resample: resize.NearestNeighbor})
+	b := out.Bounds()
+	if b.Dx() != 50 || b.Dy() != 50 {
+		t.Fatalf("resizeTo(FitFill) size = %dx%d, want exactly 50x50", b.Dx(), b.Dy())
+	}
+}
+
+func TestResizeToFillAnchorTopLeftKeepsOrigin(t *testing.T) {
+	// A bright square in the top-left corner should survive an
+	// AnchorTopLeft crop, since it keeps the (0,0) corner of the
+	// cover-resized image.
+	src := image.NewGray(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			src.SetGray(x, y, color.Gray{Y: 255})
+		}
+	}
+
+	out := resizeTo(src, Options{Width: 20, Height: 20, Fit: FitFill, Anchor: AnchorTopLeft, Resample: resize.NearestNeighbor})
+
+	r, _, _, _ := out.At(0, 0).RGBA()
+	if r == 0 {
+		t.Fatalf("resizeTo(FitFill, AnchorTopLeft) lost the top-left corner content")
+	}
+}
+
+func TestContainScaleAndFillScale(t *testing.T) {
+	bounds := image.Rect(0, 0, 200, 100)
+
+	if got := containScale(bounds, 50, 50); got != 0.25 {
+		t.Fatalf("containScale() = %v, want 0.25 (width-limited)", got)
+	}
+	if got := fillScale(bounds, 50, 50); got != 0.5 {
+		t.Fatalf("fillScale() = %v, want 0.5 (height-limited)", got)
+	}
+}
+
+func TestCropImage(t *testing.T) {
+	src := image.NewGray(image.Rect(0, 0, 10, 10))
+	src.SetGray(5, 5, color.Gray{Y: 200})
+
+	out := cropImage(src, image.Pt(3, 3), 4, 4)
+	r, _, _, _ := out.At(2, 2).RGBA() // (3+2, 3+2) in src == (5,5)
+	if r == 0 {
+		t.Fatalf("cropImage() didn't preserve the source pixel at the mapped offset")
+	}
+}
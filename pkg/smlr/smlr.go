@@ -0,0 +1,348 @@
+// Package smlr searches for the lowest output quality, in a chosen image
+// format, that keeps a resized image within a target perceptual deviation
+// of its source, so callers can shrink images without a visible quality
+// loss.
+package smlr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"runtime"
+
+	"github.com/jasonmoo/smlr/internal/compare"
+	"github.com/jasonmoo/smlr/internal/metadata"
+	"github.com/nfnt/resize"
+)
+
+// Format identifies the output encoding Optimize searches quality for.
+type Format int
+
+const (
+	JPEG Format = iota
+)
+
+// Comparator scores how visually different test is from ref; 0 means
+// identical and larger values mean more different.
+type Comparator interface {
+	Compare(ref, test image.Image) float64
+}
+
+// ComparatorFunc adapts a plain function to the Comparator interface.
+type ComparatorFunc func(ref, test image.Image) float64
+
+// Compare calls f(ref, test).
+func (f ComparatorFunc) Compare(ref, test image.Image) float64 { return f(ref, test) }
+
+// ssimComparator and msssimComparator adapt internal/compare's metrics to
+// Comparator with a fixed worker count baked in at construction, so
+// concurrent callers (e.g. Optimize calls running in smlr's batch mode)
+// each get their own value instead of racing on a shared knob.
+type ssimComparator struct{ workers int }
+
+func (c ssimComparator) Compare(ref, test image.Image) float64 {
+	return compare.Compare(ref, test, c.workers)
+}
+
+type msssimComparator struct{ workers int }
+
+func (c msssimComparator) Compare(ref, test image.Image) float64 {
+	return compare.CompareMultiScale(ref, test, c.workers)
+}
+
+// NewSSIM and NewMSSSIM build a Comparator backed by internal/compare,
+// evaluated with up to workers goroutines. Callers that run several
+// comparators concurrently (e.g. against Options.Cores) should construct
+// one of these per call rather than sharing a single value across
+// differing worker counts.
+func NewSSIM(workers int) Comparator   { return ssimComparator{workers: workers} }
+func NewMSSSIM(workers int) Comparator { return msssimComparator{workers: workers} }
+
+// SSIM and MSSSIM are the built-in in-process comparators, backed by
+// internal/compare and evaluated with up to runtime.NumCPU() goroutines.
+// SSIM is used when Options.Compare is left nil and Options.Cores governs
+// concurrency instead; see NewSSIM/NewMSSSIM for explicit control.
+var (
+	SSIM   Comparator = NewSSIM(runtime.NumCPU())
+	MSSSIM Comparator = NewMSSSIM(runtime.NumCPU())
+)
+
+// Options configures Optimize.
+type Options struct {
+	// MaxDeviation is the largest Comparator score a candidate quality may
+	// have and still be accepted.
+	MaxDeviation float64
+
+	// Width and Height resize src before searching for a quality. Leaving
+	// both zero skips resizing.
+	Width, Height uint
+	Resample      resize.InterpolationFunction
+
+	// Fit controls how Width and Height are applied when both are set.
+	// Defaults to FitScale, resize.Resize's distort-to-fit behavior.
+	Fit Fit
+
+	// Anchor selects the region kept when Fit is FitFill. Defaults to
+	// AnchorCenter.
+	Anchor Anchor
+
+	// Cores bounds how many quality probes run concurrently, and how many
+	// goroutines the Comparator may use internally. Defaults to 1.
+	Cores int
+
+	// Format selects the output encoding: JPEG, PNG, WebP, or AVIF.
+	Format Format
+
+	// Compare scores candidate quality levels against the resized source.
+	// Defaults to SSIM.
+	Compare Comparator
+
+	// Source holds the original, undecoded source file bytes. It's only
+	// needed when PreserveMetadata is set, so Optimize can recover the
+	// EXIF/ICC/XMP data that decoding src already discarded.
+	Source []byte
+
+	// PreserveMetadata carries EXIF, ICC, and XMP metadata from Source
+	// through to the result: JPEG APPn segments splice into a JPEG output,
+	// and PNG tEXt/iTXt/iCCP chunks splice into a PNG output (or an iCCP
+	// profile translates into a JPEG APP2 ICC segment for a JPEG output).
+	// Ignored if Source is empty.
+	PreserveMetadata bool
+
+	// ApplyOrientation, when PreserveMetadata finds an EXIF Orientation
+	// tag, rotates/flips the pixels to match it and clears the tag so a
+	// viewer doesn't apply it twice. Left false, the pixels are encoded
+	// as-is and the tag is copied through unchanged.
+	ApplyOrientation bool
+}
+
+// Result is the outcome of a successful Optimize call.
+type Result struct {
+	Quality   int
+	Bytes     []byte
+	Deviation float64
+}
+
+// Optimize resizes src per opts (if Width or Height are set) and searches
+// for the lowest quality, in opts.Format's Encoder, whose perceptual
+// deviation from the resized source, as scored by opts.Compare, stays
+// under opts.MaxDeviation. The comparator always scores against the
+// pristine (resized) source image, not a re-encoded reference.
+func Optimize(ctx context.Context, src image.Image, opts Options) (Result, error) {
+
+	enc, err := EncoderFor(opts.Format)
+	if err != nil {
+		return Result{}, err
+	}
+	if opts.Cores < 1 {
+		opts.Cores = 1
+	}
+	if opts.Compare == nil {
+		opts.Compare = NewSSIM(opts.Cores)
+	}
+
+	img := src
+
+	var jpegSegs []metadata.Segment
+	var pngChunks []metadata.PNGChunk
+
+	if opts.PreserveMetadata && len(opts.Source) > 0 {
+		switch {
+		case metadata.IsJPEG(opts.Source):
+			segs, err := metadata.ExtractJPEG(opts.Source)
+			if err != nil {
+				return Result{}, fmt.Errorf("smlr: reading source metadata: %w", err)
+			}
+			if opts.ApplyOrientation {
+				for i, s := range segs {
+					if s.IsEXIF() {
+						img = metadata.Apply(img, metadata.Orientation(s.Data))
+						segs[i].Data = metadata.ClearOrientation(s.Data)
+					}
+				}
+			}
+			jpegSegs = segs
+		case metadata.IsPNG(opts.Source):
+			chunks, err := metadata.ExtractPNG(opts.Source)
+			if err != nil {
+				return Result{}, fmt.Errorf("smlr: reading source metadata: %w", err)
+			}
+			pngChunks = chunks
+		}
+	}
+
+	if opts.Width > 0 || opts.Height > 0 {
+		img = resizeTo(img, opts)
+	}
+
+	loQuality, hiQuality := enc.QualityRange()
+
+	if skipsSearch(enc) {
+		out, err := encode(enc, img, hiQuality)
+		if err != nil {
+			return Result{}, fmt.Errorf("smlr: encoding result: %w", err)
+		}
+		out, err = injectMetadata(opts, out, jpegSegs, pngChunks)
+		if err != nil {
+			return Result{}, fmt.Errorf("smlr: preserving metadata: %w", err)
+		}
+		return Result{Quality: hiQuality, Bytes: out}, nil
+	}
+
+	var searchErr error
+	quality := karySearch(hiQuality, opts.Cores, func(q int) bool {
+
+		if err := ctx.Err(); err != nil {
+			searchErr = err
+			return true
+		}
+
+		candidateBytes, err := encode(enc, img, q)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		candidate, err := decode(candidateBytes)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+
+		return opts.Compare.Compare(img, candidate) < opts.MaxDeviation
+
+	})
+	if searchErr != nil {
+		return Result{}, searchErr
+	}
+	if quality < loQuality {
+		quality = loQuality
+	}
+
+	out, err := encode(enc, img, quality)
+	if err != nil {
+		return Result{}, fmt.Errorf("smlr: encoding result: %w", err)
+	}
+	outImg, err := decode(out)
+	if err != nil {
+		return Result{}, fmt.Errorf("smlr: decoding result: %w", err)
+	}
+	deviation := opts.Compare.Compare(img, outImg)
+
+	out, err = injectMetadata(opts, out, jpegSegs, pngChunks)
+	if err != nil {
+		return Result{}, fmt.Errorf("smlr: preserving metadata: %w", err)
+	}
+
+	return Result{
+		Quality:   quality,
+		Bytes:     out,
+		Deviation: deviation,
+	}, nil
+
+}
+
+// injectMetadata splices metadata recovered from Options.Source back into
+// an encoded result. A PNG source's iCCP profile is translated into a
+// JPEG APP2 ICC segment when the output format is JPEG.
+func injectMetadata(opts Options, out []byte, jpegSegs []metadata.Segment, pngChunks []metadata.PNGChunk) ([]byte, error) {
+
+	if !opts.PreserveMetadata {
+		return out, nil
+	}
+
+	switch opts.Format {
+
+	case JPEG:
+		segs := jpegSegs
+		if len(segs) == 0 && len(pngChunks) > 0 {
+			if icc, ok := metadata.ICCFromPNG(pngChunks); ok {
+				segs = []metadata.Segment{metadata.ICCSegment(icc)}
+			}
+		}
+		return metadata.SpliceJPEG(out, segs)
+
+	case PNG:
+		return metadata.InjectPNG(out, pngChunks)
+
+	default:
+		return out, nil
+
+	}
+
+}
+
+// encode runs enc against img and returns the raw encoded bytes.
+func encode(enc Encoder, img image.Image, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := enc.Encode(&buf, img, quality); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decode re-decodes encoded image bytes so a candidate can be compared
+// pixel-for-pixel against the source.
+func decode(b []byte) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// karySearch finds the smallest i in (start, n] for which f(i) is true,
+// assuming f is monotonic (false below the threshold, true at and above
+// it), by probing k points at a time instead of bisecting one at a time.
+func karySearch(n, k int, f func(int) bool) int {
+
+	if k < 2 {
+		k = 2
+	}
+
+	var search func(start, end int) int
+
+	search = func(start, end int) int {
+
+		type resp struct {
+			i  int
+			ok bool
+		}
+
+		var size, chunk int
+
+		if end-start > k {
+			chunk = (end - start) / k
+			size = k
+		} else {
+			chunk = 1
+			size = end - start
+		}
+
+		resps := make(chan resp, size)
+
+		for i := k; i > 0; i-- {
+			go func(i int) {
+				resps <- resp{i: i, ok: f(i)}
+			}(start + (i * chunk))
+		}
+
+		for i := 0; i < cap(resps); i++ {
+			r := <-resps
+			// start should always be !ok
+			// end should always be ok
+			if !r.ok && r.i > start && r.i < end {
+				start = r.i
+			} else if r.ok && r.i < end && r.i > start {
+				end = r.i
+			}
+		}
+
+		if end-start == 1 {
+			return end
+		}
+
+		return search(start, end)
+
+	}
+
+	return search(-1, n)
+
+}